@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// readBenchfmt parses the golang.org/x/perf/benchfmt text format produced by
+// `go test -bench`. Config lines (`key: value`) set labels for subsequent
+// benchmark result lines until overridden; the value of configKey is used as
+// the Measurement label. Every metric column (ns/op, B/op, a custom unit...)
+// becomes its own series, keyed by unit.
+func readBenchfmt(filename, configKey string) (map[string][]Measurement, []string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	series := make(map[string][]Measurement)
+	var units []string
+	seenUnits := make(map[string]struct{})
+
+	currentLabel := ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if key, value, ok := parseBenchfmtConfigLine(line); ok {
+			if key == configKey {
+				currentLabel = value
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+
+		// fields[0] is the benchmark name, fields[1] is the iteration count,
+		// the rest come in (value, unit) pairs.
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing value %q: %w", fields[i], err)
+			}
+			unit := fields[i+1]
+
+			series[unit] = append(series[unit], Measurement{
+				Value: value,
+				Label: currentLabel,
+			})
+			if _, ok := seenUnits[unit]; !ok {
+				seenUnits[unit] = struct{}{}
+				units = append(units, unit)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	sort.Strings(units)
+	return series, units, nil
+}
+
+// parseBenchfmtConfigLine recognizes a `key: value` configuration header line.
+func parseBenchfmtConfigLine(line string) (key, value string, ok bool) {
+	if strings.HasPrefix(line, "Benchmark") {
+		return "", "", false
+	}
+
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(line[:idx])
+	value = strings.TrimSpace(line[idx+1:])
+	if key == "" || strings.Contains(key, " ") {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// writeUnitReport renders one benchmark/CI style comparison row per metric
+// unit (ns/op, B/op, ...) in the requested --output format.
+func writeUnitReport(w io.Writer, results map[string]TimeSegmentAnalysis, units []string, threshold float64, useColor bool, format string) error {
+	switch format {
+	case "", "text":
+		writeUnitText(w, results, units, threshold, useColor)
+		return nil
+	case "json":
+		return encodeJSON(w, results)
+	case "markdown":
+		writeUnitMarkdown(w, results, units, threshold)
+		return nil
+	case "html":
+		writeUnitHTML(w, results, units, threshold)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func writeUnitText(w io.Writer, results map[string]TimeSegmentAnalysis, units []string, threshold float64, useColor bool) {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+
+	formatDifference := func(diff float64) string {
+		if !useColor || math.Abs(diff) <= threshold {
+			return fmt.Sprintf("%.2f%%", diff)
+		}
+		if diff < 0 {
+			return green.Sprintf("%.2f%%", diff)
+		}
+		return red.Sprintf("%.2f%%", diff)
+	}
+
+	fmt.Fprintln(w, "\nBenchfmt Results by Unit:")
+	fmt.Fprintln(w, strings.Repeat("-", 50))
+	for _, unit := range units {
+		analysis, ok := results[unit]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%s: baseline=%.4f experiment=%.4f diff=%s (Cohen's d=%.3f, Cliff's delta=%.3f) p=%.4f\n",
+			unit, analysis.Benchmark.Mean, analysis.Experiment.Mean, formatDifference(analysis.Difference),
+			analysis.CohensD, analysis.CliffsDelta, analysis.PValue)
+	}
+}
+
+func writeUnitMarkdown(w io.Writer, results map[string]TimeSegmentAnalysis, units []string, threshold float64) {
+	fmt.Fprintln(w, "| Unit | Baseline | Experiment | Difference | Cohen's d | Cliff's delta | P-value |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|")
+	for _, unit := range units {
+		a, ok := results[unit]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "| %s | %.4f | %.4f | %s | %.3f | %.3f | %.4f |\n",
+			unit, a.Benchmark.Mean, a.Experiment.Mean, markdownDiffCell(a.Difference, threshold), a.CohensD, a.CliffsDelta, a.PValue)
+	}
+}
+
+func writeUnitHTML(w io.Writer, results map[string]TimeSegmentAnalysis, units []string, threshold float64) {
+	fmt.Fprintln(w, "<table>")
+	fmt.Fprintln(w, "<tr><th>Unit</th><th>Baseline</th><th>Experiment</th><th>Difference</th><th>Cohen's d</th><th>Cliff's delta</th><th>P-value</th></tr>")
+	for _, unit := range units {
+		a, ok := results[unit]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%.4f</td><td>%.4f</td><td style=\"color:%s\">%.2f%%</td><td>%.3f</td><td>%.3f</td><td>%.4f</td></tr>\n",
+			html.EscapeString(unit), a.Benchmark.Mean, a.Experiment.Mean, htmlDiffColor(a.Difference, threshold), a.Difference, a.CohensD, a.CliffsDelta, a.PValue)
+	}
+	fmt.Fprintln(w, "</table>")
+}
@@ -0,0 +1,60 @@
+package main
+
+import "sort"
+
+// convertCounterSeries turns a monotonically increasing counter series (like
+// a Prometheus counter) into per-interval measurements. Samples are sorted
+// by date first. Whenever a sample is smaller than its predecessor, it's
+// treated as a counter reset (e.g. a process restart): the size of the drop
+// (the predecessor minus the sample) is added to a running correction
+// offset, so the corrected series never decreases and later samples stay
+// comparable to the ones before the reset.
+//
+// In "rate" mode each measurement becomes the average rate of increase since
+// the first sample: (correctedValue-firstValue)/Δt. In "delta" mode it
+// becomes the corrected per-step difference from the previous sample
+// instead. The first sample has no preceding interval, so the result has one
+// fewer measurement than the input; in "rate" mode, samples with the same
+// timestamp as the first one are dropped as well.
+func convertCounterSeries(measurements []Measurement, mode string) []Measurement {
+	if len(measurements) < 2 {
+		return nil
+	}
+
+	sorted := append([]Measurement(nil), measurements...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	corrected := make([]float64, len(sorted))
+	corrected[0] = sorted[0].Value
+
+	var counterCorrection float64
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Value < sorted[i-1].Value {
+			counterCorrection += sorted[i-1].Value - sorted[i].Value
+		}
+		corrected[i] = sorted[i].Value + counterCorrection
+	}
+
+	converted := make([]Measurement, 0, len(sorted)-1)
+	for i := 1; i < len(sorted); i++ {
+		var value float64
+		switch mode {
+		case "delta":
+			value = corrected[i] - corrected[i-1]
+		default: // "rate"
+			dt := sorted[i].Date.Sub(sorted[0].Date).Seconds()
+			if dt <= 0 {
+				continue
+			}
+			value = (corrected[i] - corrected[0]) / dt
+		}
+
+		converted = append(converted, Measurement{
+			Date:  sorted[i].Date,
+			Value: value,
+			Label: sorted[i].Label,
+		})
+	}
+
+	return converted
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFilter narrows a measurement set down to a date range, a time-of-day
+// window, and/or a set of weekdays. Zero-value fields mean "no restriction".
+type TimeFilter struct {
+	Since    time.Time
+	Until    time.Time
+	FromTime *time.Duration
+	ToTime   *time.Duration
+	Weekdays map[time.Weekday]struct{}
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseTimeFilter builds a TimeFilter from the --since/--until/--from-time/
+// --to-time/--weekdays options.
+func parseTimeFilter(opts Options) (TimeFilter, error) {
+	var filter TimeFilter
+
+	since, err := parseDateArg(opts.Since)
+	if err != nil {
+		return TimeFilter{}, fmt.Errorf("--since: %w", err)
+	}
+	filter.Since = since
+
+	until, err := parseDateArg(opts.Until)
+	if err != nil {
+		return TimeFilter{}, fmt.Errorf("--until: %w", err)
+	}
+	filter.Until = until
+
+	if opts.FromTime != "" {
+		fromTime, err := parseTimeOfDay(opts.FromTime)
+		if err != nil {
+			return TimeFilter{}, fmt.Errorf("--from-time: %w", err)
+		}
+		filter.FromTime = &fromTime
+	}
+
+	if opts.ToTime != "" {
+		toTime, err := parseTimeOfDay(opts.ToTime)
+		if err != nil {
+			return TimeFilter{}, fmt.Errorf("--to-time: %w", err)
+		}
+		filter.ToTime = &toTime
+	}
+
+	weekdays, err := parseWeekdays(opts.Weekdays)
+	if err != nil {
+		return TimeFilter{}, fmt.Errorf("--weekdays: %w", err)
+	}
+	filter.Weekdays = weekdays
+
+	return filter, nil
+}
+
+func parseDateArg(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	layouts := []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid hour in %q: %w", s, err)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid minute in %q: %w", s, err)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("time %q out of range", s)
+	}
+
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+func parseWeekdays(s string) (map[time.Weekday]struct{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	weekdays := make(map[time.Weekday]struct{})
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		weekday, ok := weekdayNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", part)
+		}
+		weekdays[weekday] = struct{}{}
+	}
+	return weekdays, nil
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// matches reports whether m falls within the filter's date range, time-of-day
+// window, and weekday set.
+func (f TimeFilter) matches(m Measurement) bool {
+	if !f.Since.IsZero() && m.Date.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !m.Date.Before(f.Until) {
+		return false
+	}
+
+	if f.Weekdays != nil {
+		if _, ok := f.Weekdays[m.Date.Weekday()]; !ok {
+			return false
+		}
+	}
+
+	if f.FromTime != nil || f.ToTime != nil {
+		tod := timeOfDay(m.Date)
+		switch {
+		case f.FromTime != nil && f.ToTime != nil:
+			if *f.FromTime <= *f.ToTime {
+				if tod < *f.FromTime || tod > *f.ToTime {
+					return false
+				}
+			} else {
+				// The window wraps past midnight, e.g. 21:00-09:00.
+				if tod < *f.FromTime && tod > *f.ToTime {
+					return false
+				}
+			}
+		case f.FromTime != nil:
+			// No --to-time: open-ended, from FromTime through end of day.
+			if tod < *f.FromTime {
+				return false
+			}
+		case f.ToTime != nil:
+			// No --from-time: open-ended, from start of day through ToTime.
+			if tod > *f.ToTime {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// filterMeasurements returns the subset of data that matches filter.
+func filterMeasurements(data []Measurement, filter TimeFilter) []Measurement {
+	var filtered []Measurement
+	for _, m := range data {
+		if filter.matches(m) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// parseBucketDuration parses a bucket size like "15m", "1h", or "1d" (the "d"
+// suffix isn't supported by time.ParseDuration, so it's handled separately).
+func parseBucketDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid bucket duration %q: %w", s, err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bucket duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// groupByBucket groups measurements by rounding each Date down to the
+// nearest bucket boundary, keyed by the bucket's start time as a Unix
+// timestamp.
+func groupByBucket(measurements []Measurement, bucket time.Duration) map[int64][]Measurement {
+	grouped := make(map[int64][]Measurement)
+	for _, m := range measurements {
+		key := m.Date.Truncate(bucket).Unix()
+		grouped[key] = append(grouped[key], m)
+	}
+	return grouped
+}
@@ -7,27 +7,59 @@ import (
 	"log"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/jessevdk/go-flags"
 	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
 type Options struct {
-	Baseline    string  `short:"b" long:"baseline" description:"Label for baseline series"`
-	Experiment  string  `short:"e" long:"experiment" description:"Label for experiment series"`
-	Confidence  float64 `long:"confidence" default:"0.95" description:"Confidence level for statistical tests"`
-	Threshold   float64 `long:"threshold" default:"3.0" description:"Threshold for coloring difference values (percentage)"`
-	NoColor     bool    `long:"no-color" description:"Disable colored output"`
-	Args        struct {
+	Baseline            string  `short:"b" long:"baseline" description:"Label for baseline series"`
+	Experiment          string  `short:"e" long:"experiment" description:"Label for experiment series"`
+	Confidence          float64 `long:"confidence" default:"0.95" description:"Confidence level for statistical tests"`
+	Threshold           float64 `long:"threshold" default:"3.0" description:"Threshold for coloring difference values (percentage)"`
+	NoColor             bool    `long:"no-color" description:"Disable colored output"`
+	Format              string  `long:"format" default:"csv" choice:"csv" choice:"benchfmt" description:"Input file format"`
+	ConfigKey           string  `long:"config-key" default:"commit" description:"Benchfmt config key used to select baseline/experiment labels (benchfmt format only)"`
+	Test                string  `long:"test" default:"welch" choice:"welch" choice:"mann-whitney" choice:"permutation" description:"Statistical test used to determine significance"`
+	Permutations        int     `long:"permutations" default:"10000" description:"Number of resamples for the permutation test"`
+	Percentiles         string  `long:"percentiles" description:"Comma-separated percentiles to analyze, e.g. 50,90,99"`
+	BootstrapIterations int     `long:"bootstrap-iterations" default:"2000" description:"Number of resamples for percentile confidence intervals"`
+	Counter             bool    `long:"counter" description:"Treat the Value column as a monotonically increasing counter and convert it to per-interval values before analysis"`
+	CounterMode         string  `long:"counter-mode" default:"rate" choice:"rate" choice:"delta" description:"How counter values are converted: average rate since the first sample, or per-step delta"`
+	Since               string  `long:"since" description:"Only include measurements at or after this time (2006-01-02[ 15:04:05] or RFC3339)"`
+	Until               string  `long:"until" description:"Only include measurements before this time"`
+	FromTime            string  `long:"from-time" description:"Only include measurements with time-of-day >= HH:MM (wraps past midnight if greater than --to-time)"`
+	ToTime              string  `long:"to-time" description:"Only include measurements with time-of-day <= HH:MM"`
+	Weekdays            string  `long:"weekdays" description:"Comma-separated weekdays to include, e.g. mon,tue,wed"`
+	Bucket              string  `long:"bucket" description:"Bucket size for the breakdown (e.g. 15m, 1h, 1d), replacing the hourly/daily breakdown"`
+	AllPairs            bool    `long:"all-pairs" description:"Compare every pair of labels (or, with --vs-all, --baseline against every other label) instead of requiring exactly 2 labels"`
+	VsAll               bool    `long:"vs-all" description:"With --all-pairs, compare --baseline against every other label instead of every pair"`
+	FDR                 bool    `long:"fdr" description:"Apply a Benjamini-Hochberg false-discovery-rate correction across all --all-pairs comparisons"`
+	Output              string  `long:"output" default:"text" choice:"text" choice:"json" choice:"markdown" choice:"html" description:"Output format"`
+	Out                 string  `long:"out" description:"Write output to this file instead of stdout"`
+	FailOnRegression    float64 `long:"fail-on-regression" description:"Exit with status 1 if any segment regresses by more than this percentage with a significant p-value"`
+	Args                struct {
 		InputFile string `positional-arg-name:"FILE" description:"Input CSV file"`
 	} `positional-args:"yes"`
 }
 
+// AnalysisOptions bundles the per-comparison knobs threaded through
+// analyzeTimeSeries/analyzeSegment, so adding a new statistical option
+// doesn't grow their parameter lists.
+type AnalysisOptions struct {
+	Confidence          float64
+	Test                string
+	Permutations        int
+	Percentiles         []float64
+	BootstrapIterations int
+	Bucket              time.Duration
+}
+
 type Measurement struct {
 	Date  time.Time
 	Value float64
@@ -49,11 +81,29 @@ type AnalysisResult struct {
 }
 
 type TimeSegmentAnalysis struct {
-	Benchmark   AnalysisResult
-	Experiment  AnalysisResult
-	Difference  float64
-	PValue      float64
-	Significant bool
+	Benchmark    AnalysisResult
+	Experiment   AnalysisResult
+	Difference   float64
+	PValue       float64
+	Significant  bool
+	WelchP       float64
+	MannWhitneyP float64
+	PermutationP float64
+	CohensD      float64
+	CliffsDelta  float64
+	Percentiles  []PercentileResult
+}
+
+// PercentileResult holds a single percentile's comparison, with a bootstrap
+// confidence interval on the baseline/experiment difference.
+type PercentileResult struct {
+	Percentile         float64
+	Benchmark          float64
+	Experiment         float64
+	Difference         float64
+	ConfidenceInterval [2]float64
+	PValue             float64
+	Significant        bool
 }
 
 func welchTTest(x, y []float64) (t, p float64) {
@@ -74,12 +124,15 @@ func welchTTest(x, y []float64) (t, p float64) {
 	df := math.Pow(varX/nx+varY/ny, 2) /
 		(math.Pow(varX/nx, 2)/(nx-1) + math.Pow(varY/ny, 2)/(ny-1))
 
-	dist := distuv.StudentsT{Nu: df}
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}
 	p = 2 * dist.Survival(math.Abs(t))
 
 	return t, p
 }
 
+// getUniqueLabels returns the distinct labels present in data, sorted so
+// that label resolution (and anything downstream of it, like --all-pairs
+// or --fail-on-regression) is deterministic across runs on the same input.
 func getUniqueLabels(data []Measurement) []string {
 	labelMap := make(map[string]struct{})
 	for _, m := range data {
@@ -90,6 +143,7 @@ func getUniqueLabels(data []Measurement) []string {
 	for label := range labelMap {
 		labels = append(labels, label)
 	}
+	sort.Strings(labels)
 	return labels
 }
 
@@ -173,11 +227,24 @@ func filterByLabel(data []Measurement, label string) TimeSeries {
 	}
 }
 
-func analyzeTimeSeries(benchmark, experiment TimeSeries, confidenceLevel float64) map[string]TimeSegmentAnalysis {
+func analyzeTimeSeries(benchmark, experiment TimeSeries, opts AnalysisOptions) map[string]TimeSegmentAnalysis {
 	results := make(map[string]TimeSegmentAnalysis)
 
 	// Overall analysis
-	results["overall"] = analyzeSegment(benchmark.Measurements, experiment.Measurements, confidenceLevel)
+	results["overall"] = analyzeSegment(benchmark.Measurements, experiment.Measurements, opts)
+
+	if opts.Bucket > 0 {
+		bucketBench := groupByBucket(benchmark.Measurements, opts.Bucket)
+		bucketExp := groupByBucket(experiment.Measurements, opts.Bucket)
+
+		for key, b := range bucketBench {
+			if e, ok := bucketExp[key]; ok {
+				results[fmt.Sprintf("bucket_%d", key)] = analyzeSegment(b, e, opts)
+			}
+		}
+
+		return results
+	}
 
 	// Hourly breakdown
 	hourlyBench := groupByHour(benchmark.Measurements)
@@ -186,7 +253,7 @@ func analyzeTimeSeries(benchmark, experiment TimeSeries, confidenceLevel float64
 	for hour := 0; hour < 24; hour++ {
 		if b, ok := hourlyBench[hour]; ok {
 			if e, ok := hourlyExp[hour]; ok {
-				results[fmt.Sprintf("hour_%02d", hour)] = analyzeSegment(b, e, confidenceLevel)
+				results[fmt.Sprintf("hour_%02d", hour)] = analyzeSegment(b, e, opts)
 			}
 		}
 	}
@@ -198,7 +265,7 @@ func analyzeTimeSeries(benchmark, experiment TimeSeries, confidenceLevel float64
 	for day := 0; day < 7; day++ {
 		if b, ok := dailyBench[day]; ok {
 			if e, ok := dailyExp[day]; ok {
-				results[fmt.Sprintf("day_%d", day)] = analyzeSegment(b, e, confidenceLevel)
+				results[fmt.Sprintf("day_%d", day)] = analyzeSegment(b, e, opts)
 			}
 		}
 	}
@@ -206,7 +273,7 @@ func analyzeTimeSeries(benchmark, experiment TimeSeries, confidenceLevel float64
 	return results
 }
 
-func analyzeSegment(benchmark, experiment []Measurement, confidenceLevel float64) TimeSegmentAnalysis {
+func analyzeSegment(benchmark, experiment []Measurement, opts AnalysisOptions) TimeSegmentAnalysis {
 	benchValues := measurementsToValues(benchmark)
 	expValues := measurementsToValues(experiment)
 
@@ -216,10 +283,32 @@ func analyzeSegment(benchmark, experiment []Measurement, confidenceLevel float64
 	benchStdDev := stat.StdDev(benchValues, nil)
 	expStdDev := stat.StdDev(expValues, nil)
 
-	_, pValue := welchTTest(benchValues, expValues)
+	_, welchP := welchTTest(benchValues, expValues)
+
+	// Mann-Whitney and the permutation test are only needed when selected via
+	// --test: the permutation test in particular resamples opts.Permutations
+	// times per segment, and computing it for every hourly/daily/bucket
+	// segment when it's not even the active test wastes real time on large
+	// inputs.
+	mannWhitneyP := math.NaN()
+	permutationP := math.NaN()
+	switch opts.Test {
+	case "mann-whitney":
+		_, mannWhitneyP = mannWhitneyU(benchValues, expValues)
+	case "permutation":
+		permutationP = permutationTest(benchValues, expValues, opts.Permutations)
+	}
+
+	pValue := welchP
+	switch opts.Test {
+	case "mann-whitney":
+		pValue = mannWhitneyP
+	case "permutation":
+		pValue = permutationP
+	}
 
-	benchCI := confidenceInterval(benchValues, confidenceLevel)
-	expCI := confidenceInterval(expValues, confidenceLevel)
+	benchCI := confidenceInterval(benchValues, opts.Confidence)
+	expCI := confidenceInterval(expValues, opts.Confidence)
 
 	return TimeSegmentAnalysis{
 		Benchmark: AnalysisResult{
@@ -234,9 +323,15 @@ func analyzeSegment(benchmark, experiment []Measurement, confidenceLevel float64
 			StdDev:             expStdDev,
 			ConfidenceInterval: expCI,
 		},
-		Difference:  ((expMean - benchMean) / benchMean) * 100,
-		PValue:      pValue,
-		Significant: pValue < (1 - confidenceLevel),
+		Difference:   ((expMean - benchMean) / benchMean) * 100,
+		PValue:       pValue,
+		Significant:  pValue < (1 - opts.Confidence),
+		WelchP:       welchP,
+		MannWhitneyP: mannWhitneyP,
+		PermutationP: permutationP,
+		CohensD:      cohensD(expValues, benchValues),
+		CliffsDelta:  cliffsDelta(expValues, benchValues),
+		Percentiles:  analyzePercentiles(benchValues, expValues, opts),
 	}
 }
 
@@ -270,64 +365,13 @@ func confidenceInterval(values []float64, confidenceLevel float64) [2]float64 {
 	mean := stat.Mean(values, nil)
 	stdErr := stat.StdDev(values, nil) / math.Sqrt(float64(len(values)))
 
-	dist := distuv.StudentsT{Nu: float64(len(values) - 1)}
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(len(values) - 1)}
 	tValue := dist.Quantile((1 + confidenceLevel) / 2)
 
 	margin := tValue * stdErr
 	return [2]float64{mean - margin, mean + margin}
 }
 
-func printResults(results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, threshold float64, useColor bool) {
-	green := color.New(color.FgGreen)
-	red := color.New(color.FgRed)
-
-	formatDifference := func(diff float64) string {
-		if !useColor {
-			return fmt.Sprintf("%.2f%%", diff)
-		}
-
-		if math.Abs(diff) <= threshold {
-			return fmt.Sprintf("%.2f%%", diff)
-		}
-
-		if diff < 0 {
-			return green.Sprintf("%.2f%%", diff)
-		}
-		return red.Sprintf("%.2f%%", diff)
-	}
-
-	overall := results["overall"]
-	fmt.Println("\nOverall Analysis Results:")
-	fmt.Println(strings.Repeat("-", 50))
-	fmt.Printf("Benchmark mean: %.4f (n=%d)\n", overall.Benchmark.Mean, overall.Benchmark.Count)
-	fmt.Printf("Experiment mean: %.4f (n=%d)\n", overall.Experiment.Mean, overall.Experiment.Count)
-	fmt.Printf("Difference: %s\n", formatDifference(overall.Difference))
-	fmt.Printf("P-value: %.4f\n", overall.PValue)
-
-	if showHourly {
-		fmt.Println("\nHourly Breakdown:")
-		fmt.Println(strings.Repeat("-", 50))
-		for hour := 0; hour < 24; hour++ {
-			key := fmt.Sprintf("hour_%02d", hour)
-			if analysis, ok := results[key]; ok {
-				fmt.Printf("Hour %02d: %s\n", hour, formatDifference(analysis.Difference))
-			}
-		}
-	}
-
-	if showDaily {
-		fmt.Println("\nDay of Week Breakdown:")
-		fmt.Println(strings.Repeat("-", 50))
-		days := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
-		for day := 0; day < 7; day++ {
-			key := fmt.Sprintf("day_%d", day)
-			if analysis, ok := results[key]; ok {
-				fmt.Printf("%s: %s\n", days[day], formatDifference(analysis.Difference))
-			}
-		}
-	}
-}
-
 func isTerminal() bool {
 	fileInfo, err := os.Stdout.Stat()
 	if err != nil {
@@ -336,41 +380,24 @@ func isTerminal() bool {
 	return (fileInfo.Mode() & os.ModeCharDevice) != 0
 }
 
-func main() {
-	var opts Options
-	parser := flags.NewParser(&opts, flags.Default)
-	parser.Usage = "[OPTIONS] FILE"
-
-	_, err := parser.Parse()
-	if err != nil {
-		os.Exit(1)
-	}
-
-	if opts.Args.InputFile == "" {
-		parser.WriteHelp(os.Stderr)
-		os.Exit(1)
-	}
-
-	data, err := readCSV(opts.Args.InputFile)
-	if err != nil {
-		log.Fatalf("Error reading CSV: %v", err)
-	}
-
-	labels := getUniqueLabels(data)
+// resolveLabels fills in opts.Baseline/opts.Experiment from the labels found
+// in the data when one or both were left unspecified, and validates whatever
+// the user did specify.
+func resolveLabels(labels []string, opts *Options) error {
 	if len(labels) != 2 && (opts.Baseline == "" || opts.Experiment == "") {
-		log.Fatalf("Found %d labels in data. When more than 2 labels exist, --baseline and --experiment must be specified.\nAvailable labels: %v",
+		return fmt.Errorf("found %d labels in data. When more than 2 labels exist, --baseline and --experiment must be specified.\nAvailable labels: %v",
 			len(labels), strings.Join(labels, ", "))
 	}
 
 	if opts.Baseline == "" && opts.Experiment == "" {
 		opts.Baseline = labels[0]
 		opts.Experiment = labels[1]
-		fmt.Printf("Auto-selected baseline: %s, experiment: %s\n", opts.Baseline, opts.Experiment)
+		fmt.Fprintf(os.Stderr, "Auto-selected baseline: %s, experiment: %s\n", opts.Baseline, opts.Experiment)
 	} else if opts.Baseline == "" {
 		for _, label := range labels {
 			if label != opts.Experiment {
 				opts.Baseline = label
-				fmt.Printf("Auto-selected baseline: %s\n", opts.Baseline)
+				fmt.Fprintf(os.Stderr, "Auto-selected baseline: %s\n", opts.Baseline)
 				break
 			}
 		}
@@ -378,30 +405,186 @@ func main() {
 		for _, label := range labels {
 			if label != opts.Baseline {
 				opts.Experiment = label
-				fmt.Printf("Auto-selected experiment: %s\n", opts.Experiment)
+				fmt.Fprintf(os.Stderr, "Auto-selected experiment: %s\n", opts.Experiment)
 				break
 			}
 		}
 	}
 
 	if !containsLabel(labels, opts.Baseline) || !containsLabel(labels, opts.Experiment) {
-		log.Fatalf("Specified labels not found in data. Available labels: %v", strings.Join(labels, ", "))
+		return fmt.Errorf("specified labels not found in data. Available labels: %v", strings.Join(labels, ", "))
+	}
+
+	return nil
+}
+
+func analysisOptions(opts Options) (AnalysisOptions, error) {
+	percentiles, err := parsePercentiles(opts.Percentiles)
+	if err != nil {
+		return AnalysisOptions{}, err
+	}
+
+	bucket, err := parseBucketDuration(opts.Bucket)
+	if err != nil {
+		return AnalysisOptions{}, err
+	}
+
+	return AnalysisOptions{
+		Confidence:          opts.Confidence,
+		Test:                opts.Test,
+		Permutations:        opts.Permutations,
+		Percentiles:         percentiles,
+		BootstrapIterations: opts.BootstrapIterations,
+		Bucket:              bucket,
+	}, nil
+}
+
+// openOutput returns os.Stdout, or a newly created file when --out names one.
+// The caller is responsible for closing the returned file (if any).
+func openOutput(path string) (*os.File, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+func runCSV(opts Options) {
+	data, err := readCSV(opts.Args.InputFile)
+	if err != nil {
+		log.Fatalf("Error reading CSV: %v", err)
+	}
+
+	timeFilter, err := parseTimeFilter(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = filterMeasurements(data, timeFilter)
+
+	labels := getUniqueLabels(data)
+	if err := resolveLabels(labels, &opts); err != nil {
+		log.Fatal(err)
 	}
 
 	baseline := filterByLabel(data, opts.Baseline)
 	experiment := filterByLabel(data, opts.Experiment)
 
+	if opts.Counter {
+		baseline.Measurements = convertCounterSeries(baseline.Measurements, opts.CounterMode)
+		experiment.Measurements = convertCounterSeries(experiment.Measurements, opts.CounterMode)
+	}
+
 	if len(baseline.Measurements) == 0 || len(experiment.Measurements) == 0 {
 		log.Fatalf("No data found for one or both labels")
 	}
 
-	analysis := analyzeTimeSeries(baseline, experiment, opts.Confidence)
+	analysisOpts, err := analysisOptions(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	analysis := analyzeTimeSeries(baseline, experiment, analysisOpts)
 
 	timeRange := baseline.MaxDate.Sub(baseline.MinDate)
-	showHourly := timeRange >= 24*time.Hour
-	showDaily := timeRange >= 7*24*time.Hour
+	showHourly := analysisOpts.Bucket == 0 && timeRange >= 24*time.Hour
+	showDaily := analysisOpts.Bucket == 0 && timeRange >= 7*24*time.Hour
+
+	useColor := !opts.NoColor && isTerminal()
+
+	reporter, err := reporterFor(opts.Output, useColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out, err := openOutput(opts.Out)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	if err := reporter.Report(out, analysis, showHourly, showDaily, analysisOpts.Bucket, opts.Threshold); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if opts.FailOnRegression > 0 && anyRegression(analysis, opts.FailOnRegression, opts.Confidence) {
+		os.Exit(1)
+	}
+}
+
+func runBenchfmt(opts Options) {
+	series, units, err := readBenchfmt(opts.Args.InputFile, opts.ConfigKey)
+	if err != nil {
+		log.Fatalf("Error reading benchfmt input: %v", err)
+	}
+	if len(units) == 0 {
+		log.Fatalf("No benchmark results found in %s", opts.Args.InputFile)
+	}
+
+	var allMeasurements []Measurement
+	for _, measurements := range series {
+		allMeasurements = append(allMeasurements, measurements...)
+	}
+	labels := getUniqueLabels(allMeasurements)
+	if err := resolveLabels(labels, &opts); err != nil {
+		log.Fatal(err)
+	}
+
+	analysisOpts, err := analysisOptions(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	useColor := !opts.NoColor && isTerminal()
+	results := make(map[string]TimeSegmentAnalysis, len(units))
+	for _, unit := range units {
+		baseline := filterByLabel(series[unit], opts.Baseline).Measurements
+		experiment := filterByLabel(series[unit], opts.Experiment).Measurements
+		if len(baseline) == 0 || len(experiment) == 0 {
+			continue
+		}
+		results[unit] = analyzeSegment(baseline, experiment, analysisOpts)
+	}
 
-	printResults(analysis, showHourly, showDaily, opts.Threshold, useColor)
+	out, err := openOutput(opts.Out)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	if err := writeUnitReport(out, results, units, opts.Threshold, useColor, opts.Output); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if opts.FailOnRegression > 0 && anyRegression(results, opts.FailOnRegression, opts.Confidence) {
+		os.Exit(1)
+	}
+}
+
+func main() {
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.Usage = "[OPTIONS] FILE"
+
+	_, err := parser.Parse()
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if opts.Args.InputFile == "" {
+		parser.WriteHelp(os.Stderr)
+		os.Exit(1)
+	}
+
+	if opts.Format == "benchfmt" {
+		runBenchfmt(opts)
+		return
+	}
+	if opts.AllPairs {
+		runAllPairsMode(opts)
+		return
+	}
+	runCSV(opts)
 }
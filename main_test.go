@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func toMeasurements(values []float64) []Measurement {
+	out := make([]Measurement, len(values))
+	for i, v := range values {
+		out[i] = Measurement{Date: time.Unix(int64(i), 0), Value: v}
+	}
+	return out
+}
+
+// TestWelchTTestUsesStandardT guards against distuv.StudentsT being built
+// without Sigma: 1 (it defaults to 0, which collapses Survival to a step
+// function at 0/1 instead of a real Student's t p-value): two samples with
+// overlapping, noisy distributions and a small mean difference should not
+// come out "infinitely significant".
+func TestWelchTTestUsesStandardT(t *testing.T) {
+	x := []float64{8, 12, 9, 11, 10, 13, 7, 12, 9, 11}
+	y := []float64{9, 13, 10, 12, 11, 14, 8, 13, 10, 12}
+
+	_, p := welchTTest(x, y)
+	if p <= 0.05 {
+		t.Errorf("expected a non-significant p-value for heavily overlapping samples, got %v", p)
+	}
+	if p > 1 {
+		t.Errorf("p-value must be <= 1, got %v", p)
+	}
+}
+
+// TestConfidenceIntervalIsNotDegenerate guards against the same missing-
+// Sigma bug in confidenceInterval: without Sigma: 1, Quantile always
+// collapses to Mu (0), making every CI exactly [mean, mean] regardless of
+// spread.
+func TestConfidenceIntervalIsNotDegenerate(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	ci := confidenceInterval(values, 0.95)
+	if ci[0] == ci[1] {
+		t.Fatalf("expected a non-degenerate confidence interval for spread-out values, got %v", ci)
+	}
+}
+
+func TestAnalyzeSegmentOnlyRunsSelectedTest(t *testing.T) {
+	bench := toMeasurements([]float64{1, 2, 3, 4, 5})
+	exp := toMeasurements([]float64{2, 3, 4, 5, 6})
+
+	cases := []struct {
+		test               string
+		wantMannWhitneyNaN bool
+		wantPermutationNaN bool
+	}{
+		{"welch", true, true},
+		{"mann-whitney", false, true},
+		{"permutation", true, false},
+	}
+
+	for _, c := range cases {
+		opts := AnalysisOptions{Confidence: 0.95, Test: c.test, Permutations: 100}
+		result := analyzeSegment(bench, exp, opts)
+
+		if got := math.IsNaN(result.MannWhitneyP); got != c.wantMannWhitneyNaN {
+			t.Errorf("test=%s: MannWhitneyP NaN=%v, want %v", c.test, got, c.wantMannWhitneyNaN)
+		}
+		if got := math.IsNaN(result.PermutationP); got != c.wantPermutationNaN {
+			t.Errorf("test=%s: PermutationP NaN=%v, want %v", c.test, got, c.wantPermutationNaN)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// PairwiseResult is one cell of the --all-pairs comparison matrix.
+type PairwiseResult struct {
+	Baseline   string
+	Experiment string
+	Analysis   TimeSegmentAnalysis
+}
+
+// labelMeasurements returns label's measurements from data, applying the
+// --counter conversion first if requested.
+func labelMeasurements(data []Measurement, label string, opts Options) []Measurement {
+	measurements := filterByLabel(data, label).Measurements
+	if opts.Counter {
+		measurements = convertCounterSeries(measurements, opts.CounterMode)
+	}
+	return measurements
+}
+
+// runAllPairs computes a pairwise comparison for every ordered label pair,
+// or (when vsAll is set) just opts.Baseline against every other label.
+func runAllPairs(data []Measurement, labels []string, opts Options, analysisOpts AnalysisOptions, vsAll bool) []PairwiseResult {
+	var results []PairwiseResult
+
+	if vsAll {
+		baseline := labelMeasurements(data, opts.Baseline, opts)
+		for _, label := range labels {
+			if label == opts.Baseline {
+				continue
+			}
+			experiment := labelMeasurements(data, label, opts)
+			if len(baseline) == 0 || len(experiment) == 0 {
+				continue
+			}
+			results = append(results, PairwiseResult{
+				Baseline:   opts.Baseline,
+				Experiment: label,
+				Analysis:   analyzeSegment(baseline, experiment, analysisOpts),
+			})
+		}
+		return results
+	}
+
+	for _, b := range labels {
+		for _, e := range labels {
+			if b == e {
+				continue
+			}
+			baseline := labelMeasurements(data, b, opts)
+			experiment := labelMeasurements(data, e, opts)
+			if len(baseline) == 0 || len(experiment) == 0 {
+				continue
+			}
+			results = append(results, PairwiseResult{
+				Baseline:   b,
+				Experiment: e,
+				Analysis:   analyzeSegment(baseline, experiment, analysisOpts),
+			})
+		}
+	}
+	return results
+}
+
+// applyBenjaminiHochberg returns, for each result, whether it's significant
+// after a Benjamini-Hochberg false-discovery-rate correction at level alpha:
+// sort the p-values ascending, find the largest i with p(i) <= (i/k)*alpha,
+// and flag every result at or below that p-value.
+func applyBenjaminiHochberg(results []PairwiseResult, alpha float64) []bool {
+	type ranked struct {
+		index int
+		p     float64
+	}
+
+	k := len(results)
+	sorted := make([]ranked, k)
+	for i, r := range results {
+		sorted[i] = ranked{i, r.Analysis.PValue}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].p < sorted[j].p })
+
+	threshold := -1.0
+	for i := k - 1; i >= 0; i-- {
+		rank := i + 1
+		if sorted[i].p <= (float64(rank)/float64(k))*alpha {
+			threshold = sorted[i].p
+			break
+		}
+	}
+
+	significant := make([]bool, k)
+	if threshold < 0 {
+		return significant
+	}
+	for _, s := range sorted {
+		significant[s.index] = s.p <= threshold
+	}
+	return significant
+}
+
+// matrixRow is the structured form of one PairwiseResult, used by the
+// --output json encoding.
+type matrixRow struct {
+	Baseline    string
+	Experiment  string
+	Significant bool
+	Analysis    TimeSegmentAnalysis
+}
+
+// writeMatrixReport renders the pairwise comparison matrix in the requested
+// --output format, marking entries that survived the significance test (and,
+// with --fdr, the FDR correction).
+func writeMatrixReport(w io.Writer, results []PairwiseResult, significant []bool, threshold float64, useColor bool, format string) error {
+	switch format {
+	case "", "text":
+		writeMatrixText(w, results, significant, threshold, useColor)
+		return nil
+	case "json":
+		rows := make([]matrixRow, len(results))
+		for i, r := range results {
+			rows[i] = matrixRow{Baseline: r.Baseline, Experiment: r.Experiment, Significant: significant[i], Analysis: r.Analysis}
+		}
+		return encodeJSON(w, rows)
+	case "markdown":
+		writeMatrixMarkdown(w, results, significant, threshold)
+		return nil
+	case "html":
+		writeMatrixHTML(w, results, significant, threshold)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func writeMatrixText(w io.Writer, results []PairwiseResult, significant []bool, threshold float64, useColor bool) {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+
+	formatDifference := func(diff float64) string {
+		if !useColor || math.Abs(diff) <= threshold {
+			return fmt.Sprintf("%.2f%%", diff)
+		}
+		if diff < 0 {
+			return green.Sprintf("%.2f%%", diff)
+		}
+		return red.Sprintf("%.2f%%", diff)
+	}
+
+	fmt.Fprintln(w, "\nPairwise Comparison Matrix:")
+	fmt.Fprintln(w, strings.Repeat("-", 50))
+	for i, r := range results {
+		marker := ""
+		if significant[i] {
+			marker = " *"
+		}
+		fmt.Fprintf(w, "%s -> %s: diff=%s p=%.4f%s\n",
+			r.Baseline, r.Experiment, formatDifference(r.Analysis.Difference), r.Analysis.PValue, marker)
+	}
+}
+
+func writeMatrixMarkdown(w io.Writer, results []PairwiseResult, significant []bool, threshold float64) {
+	fmt.Fprintln(w, "| Baseline | Experiment | Difference | Cohen's d | Cliff's delta | P-value | Significant |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|")
+	for i, r := range results {
+		a := r.Analysis
+		fmt.Fprintf(w, "| %s | %s | %s | %.3f | %.3f | %.4f | %v |\n",
+			r.Baseline, r.Experiment, markdownDiffCell(a.Difference, threshold), a.CohensD, a.CliffsDelta, a.PValue, significant[i])
+	}
+}
+
+func writeMatrixHTML(w io.Writer, results []PairwiseResult, significant []bool, threshold float64) {
+	fmt.Fprintln(w, "<table>")
+	fmt.Fprintln(w, "<tr><th>Baseline</th><th>Experiment</th><th>Difference</th><th>Cohen's d</th><th>Cliff's delta</th><th>P-value</th><th>Significant</th></tr>")
+	for i, r := range results {
+		a := r.Analysis
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td style=\"color:%s\">%.2f%%</td><td>%.3f</td><td>%.3f</td><td>%.4f</td><td>%v</td></tr>\n",
+			html.EscapeString(r.Baseline), html.EscapeString(r.Experiment), htmlDiffColor(a.Difference, threshold), a.Difference, a.CohensD, a.CliffsDelta, a.PValue, significant[i])
+	}
+	fmt.Fprintln(w, "</table>")
+}
+
+// anyPairwiseRegression reports whether any pairwise comparison regresses by
+// more than pct while still marked significant, for --fail-on-regression.
+// significant must be the slice already used to render the report (the
+// --fdr-corrected one when --fdr is set), so this agrees with what was shown.
+func anyPairwiseRegression(results []PairwiseResult, significant []bool, pct float64) bool {
+	for i, r := range results {
+		if r.Analysis.Difference > pct && significant[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func runAllPairsMode(opts Options) {
+	data, err := readCSV(opts.Args.InputFile)
+	if err != nil {
+		log.Fatalf("Error reading CSV: %v", err)
+	}
+
+	timeFilter, err := parseTimeFilter(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = filterMeasurements(data, timeFilter)
+
+	labels := getUniqueLabels(data)
+	if len(labels) < 2 {
+		log.Fatalf("Need at least 2 labels for --all-pairs, found %d", len(labels))
+	}
+
+	if opts.VsAll && opts.Baseline == "" {
+		log.Fatalf("--vs-all requires --baseline to be specified")
+	}
+
+	analysisOpts, err := analysisOptions(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	results := runAllPairs(data, labels, opts, analysisOpts, opts.VsAll)
+	if len(results) == 0 {
+		log.Fatalf("No comparable label pairs found")
+	}
+
+	var significant []bool
+	if opts.FDR {
+		significant = applyBenjaminiHochberg(results, 1-opts.Confidence)
+	} else {
+		significant = make([]bool, len(results))
+		for i, r := range results {
+			significant[i] = r.Analysis.Significant
+		}
+	}
+
+	useColor := !opts.NoColor && isTerminal()
+
+	out, err := openOutput(opts.Out)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	if out != os.Stdout {
+		defer out.Close()
+	}
+
+	if err := writeMatrixReport(out, results, significant, opts.Threshold, useColor, opts.Output); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if opts.FailOnRegression > 0 && anyPairwiseRegression(results, significant, opts.FailOnRegression) {
+		os.Exit(1)
+	}
+}
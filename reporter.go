@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Reporter renders a set of segment analyses (overall plus whatever
+// hourly/daily/bucket breakdown is active) to w.
+type Reporter interface {
+	Report(w io.Writer, results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration, threshold float64) error
+}
+
+// reporterFor resolves the --output flag to a Reporter.
+func reporterFor(format string, useColor bool) (Reporter, error) {
+	switch format {
+	case "", "text":
+		return TextReporter{UseColor: useColor}, nil
+	case "json":
+		return JSONReporter{}, nil
+	case "markdown":
+		return MarkdownReporter{}, nil
+	case "html":
+		return HTMLReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// segmentRow pairs a human-readable label with its segment analysis, in the
+// order a breakdown should be printed.
+type segmentRow struct {
+	Label    string
+	Analysis TimeSegmentAnalysis
+}
+
+// segmentRows picks out the breakdown rows (bucket, else hourly/daily) that
+// should follow the overall row, shared by every Reporter.
+func segmentRows(results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration) []segmentRow {
+	var rows []segmentRow
+
+	if bucket > 0 {
+		var keys []int64
+		for key := range results {
+			var ts int64
+			if _, err := fmt.Sscanf(key, "bucket_%d", &ts); err == nil {
+				keys = append(keys, ts)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		for _, ts := range keys {
+			label := time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05")
+			rows = append(rows, segmentRow{label, results[fmt.Sprintf("bucket_%d", ts)]})
+		}
+		return rows
+	}
+
+	if showHourly {
+		for hour := 0; hour < 24; hour++ {
+			key := fmt.Sprintf("hour_%02d", hour)
+			if analysis, ok := results[key]; ok {
+				rows = append(rows, segmentRow{fmt.Sprintf("Hour %02d", hour), analysis})
+			}
+		}
+	}
+
+	if showDaily {
+		days := []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+		for day := 0; day < 7; day++ {
+			key := fmt.Sprintf("day_%d", day)
+			if analysis, ok := results[key]; ok {
+				rows = append(rows, segmentRow{days[day], analysis})
+			}
+		}
+	}
+
+	return rows
+}
+
+// anyRegression reports whether any segment's difference exceeds pct with a
+// p-value below 1-confidenceLevel, for --fail-on-regression.
+func anyRegression(results map[string]TimeSegmentAnalysis, pct, confidenceLevel float64) bool {
+	for _, analysis := range results {
+		if analysis.Difference > pct && analysis.PValue < (1-confidenceLevel) {
+			return true
+		}
+	}
+	return false
+}
+
+// TextReporter renders the original human-readable console report.
+type TextReporter struct {
+	UseColor bool
+}
+
+func (r TextReporter) Report(w io.Writer, results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration, threshold float64) error {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+
+	formatDifference := func(diff float64) string {
+		if !r.UseColor || math.Abs(diff) <= threshold {
+			return fmt.Sprintf("%.2f%%", diff)
+		}
+		if diff < 0 {
+			return green.Sprintf("%.2f%%", diff)
+		}
+		return red.Sprintf("%.2f%%", diff)
+	}
+
+	formatEffectSize := func(analysis TimeSegmentAnalysis) string {
+		return fmt.Sprintf("Cohen's d=%.3f, Cliff's delta=%.3f", analysis.CohensD, analysis.CliffsDelta)
+	}
+
+	overall := results["overall"]
+	fmt.Fprintln(w, "\nOverall Analysis Results:")
+	fmt.Fprintln(w, strings.Repeat("-", 50))
+	fmt.Fprintf(w, "Benchmark mean: %.4f (n=%d)\n", overall.Benchmark.Mean, overall.Benchmark.Count)
+	fmt.Fprintf(w, "Experiment mean: %.4f (n=%d)\n", overall.Experiment.Mean, overall.Experiment.Count)
+	fmt.Fprintf(w, "Difference: %s (%s)\n", formatDifference(overall.Difference), formatEffectSize(overall))
+	fmt.Fprintf(w, "P-value: %.4f\n", overall.PValue)
+
+	if len(overall.Percentiles) > 0 {
+		fmt.Fprintln(w, "\nPercentile Breakdown:")
+		fmt.Fprintln(w, strings.Repeat("-", 50))
+		for _, pr := range overall.Percentiles {
+			fmt.Fprintf(w, "p%g: %s (CI [%.2f%%, %.2f%%], p=%.4f)\n",
+				pr.Percentile, formatDifference(pr.Difference), pr.ConfidenceInterval[0], pr.ConfidenceInterval[1], pr.PValue)
+		}
+	}
+
+	sections := []struct {
+		title string
+		rows  []segmentRow
+	}{
+		{"Bucket Breakdown", segmentRows(results, false, false, bucket)},
+		{"Hourly Breakdown", segmentRows(results, showHourly, false, 0)},
+		{"Day of Week Breakdown", segmentRows(results, false, showDaily, 0)},
+	}
+	for _, section := range sections {
+		if len(section.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		fmt.Fprintln(w, strings.Repeat("-", 50))
+		for _, row := range section.rows {
+			fmt.Fprintf(w, "%s: %s (%s)\n", row.Label, formatDifference(row.Analysis.Difference), formatEffectSize(row.Analysis))
+		}
+	}
+
+	return nil
+}
+
+// JSONReporter emits the full results map, with every field (means, stddev,
+// CI, p-values, effect sizes, counts), for CI jobs to gate on.
+type JSONReporter struct{}
+
+func (r JSONReporter) Report(w io.Writer, results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration, threshold float64) error {
+	return encodeJSON(w, results)
+}
+
+// encodeJSON writes v as indented JSON after replacing any NaN/Inf float64
+// (e.g. a Mann-Whitney or permutation p-value from a zero-variance segment)
+// with null, since encoding/json refuses to encode them at all. Unlike
+// marshaling a sanitized copy built from map[string]interface{}, this walks v
+// directly so struct field order and nil slices/maps survive untouched.
+func encodeJSON(w io.Writer, v interface{}) error {
+	raw, err := sanitizeJSON(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err = w.Write(indented.Bytes())
+	return err
+}
+
+// sanitizeJSON renders v as JSON, replacing NaN/Inf float64 values with null,
+// while otherwise matching what encoding/json would produce: struct fields in
+// declaration order, and nil slices/maps as null rather than [] or {}.
+func sanitizeJSON(v reflect.Value) (json.RawMessage, error) {
+	if !v.IsValid() {
+		return json.RawMessage("null"), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return json.RawMessage("null"), nil
+		}
+		return json.Marshal(f)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return sanitizeJSON(v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		wroteField := false
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			value, err := sanitizeJSON(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if wroteField {
+				buf.WriteByte(',')
+			}
+			wroteField = true
+			key, _ := json.Marshal(t.Field(i).Name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case reflect.Map:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, mapKey := range keys {
+			value, err := sanitizeJSON(v.MapIndex(mapKey))
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(fmt.Sprint(mapKey.Interface()))
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return sanitizeJSONArray(v)
+	case reflect.Array:
+		return sanitizeJSONArray(v)
+	default:
+		return json.Marshal(v.Interface())
+	}
+}
+
+func sanitizeJSONArray(v reflect.Value) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		value, err := sanitizeJSON(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// MarkdownReporter renders a benchstat-style table, marking differences that
+// cross threshold with a red/green indicator.
+type MarkdownReporter struct{}
+
+func (r MarkdownReporter) Report(w io.Writer, results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration, threshold float64) error {
+	fmt.Fprintln(w, "| Segment | Benchmark | Experiment | Difference | Cohen's d | Cliff's delta | P-value |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|---|")
+
+	overall := results["overall"]
+	fmt.Fprintf(w, "| Overall | %.4f | %.4f | %s | %.3f | %.3f | %.4f |\n",
+		overall.Benchmark.Mean, overall.Experiment.Mean, markdownDiffCell(overall.Difference, threshold),
+		overall.CohensD, overall.CliffsDelta, overall.PValue)
+
+	for _, row := range segmentRows(results, showHourly, showDaily, bucket) {
+		a := row.Analysis
+		fmt.Fprintf(w, "| %s | %.4f | %.4f | %s | %.3f | %.3f | %.4f |\n",
+			row.Label, a.Benchmark.Mean, a.Experiment.Mean, markdownDiffCell(a.Difference, threshold), a.CohensD, a.CliffsDelta, a.PValue)
+	}
+
+	return nil
+}
+
+func markdownDiffCell(diff, threshold float64) string {
+	text := fmt.Sprintf("%.2f%%", diff)
+	if math.Abs(diff) <= threshold {
+		return text
+	}
+	if diff < 0 {
+		return "🟢 " + text
+	}
+	return "🔴 " + text
+}
+
+// HTMLReporter renders a benchstat-style HTML table, coloring differences
+// that cross threshold red (regression) or green (improvement).
+type HTMLReporter struct{}
+
+func (r HTMLReporter) Report(w io.Writer, results map[string]TimeSegmentAnalysis, showHourly, showDaily bool, bucket time.Duration, threshold float64) error {
+	fmt.Fprintln(w, "<table>")
+	fmt.Fprintln(w, "<tr><th>Segment</th><th>Benchmark</th><th>Experiment</th><th>Difference</th><th>Cohen's d</th><th>Cliff's delta</th><th>P-value</th></tr>")
+
+	overall := results["overall"]
+	fmt.Fprintf(w, "<tr><td>Overall</td><td>%.4f</td><td>%.4f</td><td style=\"color:%s\">%.2f%%</td><td>%.3f</td><td>%.3f</td><td>%.4f</td></tr>\n",
+		overall.Benchmark.Mean, overall.Experiment.Mean, htmlDiffColor(overall.Difference, threshold), overall.Difference,
+		overall.CohensD, overall.CliffsDelta, overall.PValue)
+
+	for _, row := range segmentRows(results, showHourly, showDaily, bucket) {
+		a := row.Analysis
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%.4f</td><td>%.4f</td><td style=\"color:%s\">%.2f%%</td><td>%.3f</td><td>%.3f</td><td>%.4f</td></tr>\n",
+			html.EscapeString(row.Label), a.Benchmark.Mean, a.Experiment.Mean, htmlDiffColor(a.Difference, threshold), a.Difference, a.CohensD, a.CliffsDelta, a.PValue)
+	}
+
+	fmt.Fprintln(w, "</table>")
+	return nil
+}
+
+func htmlDiffColor(diff, threshold float64) string {
+	if math.Abs(diff) <= threshold {
+		return "inherit"
+	}
+	if diff < 0 {
+		return "green"
+	}
+	return "red"
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestJSONReporterHandlesNaNAndInf(t *testing.T) {
+	results := map[string]TimeSegmentAnalysis{
+		"overall": {
+			PValue:       math.NaN(),
+			WelchP:       math.NaN(),
+			MannWhitneyP: math.Inf(1),
+			CohensD:      math.Inf(-1),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, results, false, false, 0, 3); err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+
+	var decoded map[string]map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	overall := decoded["overall"]
+	for _, field := range []string{"PValue", "WelchP", "MannWhitneyP", "CohensD"} {
+		if overall[field] != nil {
+			t.Errorf("expected %s to encode as null, got %v", field, overall[field])
+		}
+	}
+}
+
+// TestEncodeJSONPreservesNilSlices guards against sanitizeJSON turning a nil
+// slice (e.g. Percentiles when --percentiles wasn't passed) into a non-nil
+// empty array: callers that check for JSON null should keep seeing it.
+func TestEncodeJSONPreservesNilSlices(t *testing.T) {
+	results := map[string]TimeSegmentAnalysis{
+		"overall": {Percentiles: nil},
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONReporter{}).Report(&buf, results, false, false, 0, 3); err != nil {
+		t.Fatalf("Report returned error: %v", err)
+	}
+
+	var decoded map[string]map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if got, ok := decoded["overall"]["Percentiles"]; ok && got != nil {
+		t.Errorf("expected nil Percentiles to encode as null, got %v", got)
+	}
+}
+
+// TestEncodeJSONPreservesFieldOrder guards against encodeJSON going through
+// map[string]interface{} (which encoding/json always serializes with
+// alphabetically sorted keys), losing the struct's declared field order.
+func TestEncodeJSONPreservesFieldOrder(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, TimeSegmentAnalysis{}); err != nil {
+		t.Fatalf("encodeJSON returned error: %v", err)
+	}
+
+	benchmarkIdx := bytes.Index(buf.Bytes(), []byte(`"Benchmark"`))
+	pValueIdx := bytes.Index(buf.Bytes(), []byte(`"PValue"`))
+	if benchmarkIdx < 0 || pValueIdx < 0 {
+		t.Fatalf("expected both Benchmark and PValue fields in output, got %s", buf.String())
+	}
+	if benchmarkIdx > pValueIdx {
+		t.Errorf("expected Benchmark (declared first) to appear before PValue, got %s", buf.String())
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// mannWhitneyU computes the Mann-Whitney U statistic for x relative to y
+// (ranking the pooled sample with average ranks for ties) and its two-sided
+// p-value from the normal approximation with a tie correction.
+func mannWhitneyU(x, y []float64) (u, p float64) {
+	n1 := float64(len(x))
+	n2 := float64(len(y))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type sample struct {
+		value float64
+		group int
+	}
+	pooled := make([]sample, 0, len(x)+len(y))
+	for _, v := range x {
+		pooled = append(pooled, sample{v, 0})
+	}
+	for _, v := range y {
+		pooled = append(pooled, sample{v, 1})
+	}
+	sort.Slice(pooled, func(i, j int) bool { return pooled[i].value < pooled[j].value })
+
+	ranks := make([]float64, len(pooled))
+	var tieCorrection float64
+	for i := 0; i < len(pooled); {
+		j := i
+		for j < len(pooled) && pooled[j].value == pooled[i].value {
+			j++
+		}
+		avgRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ranks[k] = avgRank
+		}
+		t := float64(j - i)
+		tieCorrection += t*t*t - t
+		i = j
+	}
+
+	var r1 float64
+	for i, s := range pooled {
+		if s.group == 0 {
+			r1 += ranks[i]
+		}
+	}
+
+	u1 := r1 - n1*(n1+1)/2
+	n := n1 + n2
+	meanU := n1 * n2 / 2
+	varU := (n1 * n2 / 12) * ((n + 1) - tieCorrection/(n*(n-1)))
+	if varU <= 0 {
+		return u1, 1
+	}
+
+	z := (u1 - meanU) / math.Sqrt(varU)
+	p = 2 * (distuv.Normal{Mu: 0, Sigma: 1}).Survival(math.Abs(z))
+	return u1, p
+}
+
+// permutationTest pools x and y, shuffles iterations times and resplits into
+// the original group sizes, and reports (count+1)/(iterations+1), where count
+// is the number of resamples whose absolute mean difference is at least as
+// large as the one observed.
+func permutationTest(x, y []float64, iterations int) float64 {
+	observed := math.Abs(stat.Mean(x, nil) - stat.Mean(y, nil))
+
+	n1 := len(x)
+	pooled := make([]float64, 0, len(x)+len(y))
+	pooled = append(pooled, x...)
+	pooled = append(pooled, y...)
+
+	shuffled := make([]float64, len(pooled))
+	count := 0
+	for i := 0; i < iterations; i++ {
+		copy(shuffled, pooled)
+		rand.Shuffle(len(shuffled), func(a, b int) {
+			shuffled[a], shuffled[b] = shuffled[b], shuffled[a]
+		})
+
+		meanA := stat.Mean(shuffled[:n1], nil)
+		meanB := stat.Mean(shuffled[n1:], nil)
+		if math.Abs(meanA-meanB) >= observed {
+			count++
+		}
+	}
+
+	return float64(count+1) / float64(iterations+1)
+}
+
+// cohensD returns the standardized mean difference between x and y using the
+// pooled standard deviation.
+func cohensD(x, y []float64) float64 {
+	n1 := float64(len(x))
+	n2 := float64(len(y))
+	if n1 < 2 || n2 < 2 {
+		return 0
+	}
+
+	pooledSD := math.Sqrt(((n1-1)*stat.Variance(x, nil) + (n2-1)*stat.Variance(y, nil)) / (n1 + n2 - 2))
+	if pooledSD == 0 {
+		return 0
+	}
+
+	return (stat.Mean(x, nil) - stat.Mean(y, nil)) / pooledSD
+}
+
+// cliffsDelta returns the non-parametric effect size (#{x>y} - #{x<y}) /
+// (n1*n2), computed in O((n1+n2)log(n1+n2)) by sorting y and binary searching
+// for each x.
+func cliffsDelta(x, y []float64) float64 {
+	n1 := float64(len(x))
+	n2 := float64(len(y))
+	if n1 == 0 || n2 == 0 {
+		return 0
+	}
+
+	sortedY := append([]float64(nil), y...)
+	sort.Float64s(sortedY)
+
+	var greater, less float64
+	for _, xv := range x {
+		lessThan := sort.SearchFloat64s(sortedY, xv)
+		greaterThan := len(sortedY) - sort.Search(len(sortedY), func(i int) bool { return sortedY[i] > xv })
+		greater += float64(lessThan)
+		less += float64(greaterThan)
+	}
+
+	return (greater - less) / (n1 * n2)
+}
+
+// parsePercentiles parses a comma-separated list like "50,90,99" into its
+// percentile values. An empty string yields no percentiles.
+func parsePercentiles(s string) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	percentiles := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q: %w", part, err)
+		}
+		if p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("percentile %v must be between 0 and 100", p)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// percentile computes the p-th percentile (0-100) of values using linear
+// interpolation between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := (p / 100) * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[upper]-sorted[lower])
+}
+
+// resample draws a bootstrap sample (with replacement) of the same size as values.
+func resample(values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i := range out {
+		out[i] = values[rand.Intn(len(values))]
+	}
+	return out
+}
+
+// bootstrapPercentileDiff resamples benchmark/experiment with replacement
+// `iterations` times, recomputes the p-th percentile percentage difference
+// each time, and returns a confidence interval on that difference (the
+// confidenceLevel quantiles of the resampled distribution) along with a
+// two-sided p-value for the difference being non-zero.
+func bootstrapPercentileDiff(benchmark, experiment []float64, p float64, iterations int, confidenceLevel float64) (ci [2]float64, pValue float64) {
+	if iterations <= 0 || len(benchmark) == 0 || len(experiment) == 0 {
+		return [2]float64{0, 0}, 1
+	}
+
+	diffs := make([]float64, iterations)
+	for i := 0; i < iterations; i++ {
+		benchP := percentile(resample(benchmark), p)
+		expP := percentile(resample(experiment), p)
+		if benchP == 0 {
+			diffs[i] = 0
+			continue
+		}
+		diffs[i] = ((expP - benchP) / benchP) * 100
+	}
+	sort.Float64s(diffs)
+
+	lowerQ := (1 - confidenceLevel) / 2
+	upperQ := 1 - lowerQ
+	lowerIdx := int(lowerQ * float64(iterations-1))
+	upperIdx := int(upperQ * float64(iterations-1))
+	ci = [2]float64{diffs[lowerIdx], diffs[upperIdx]}
+
+	var countLE, countGE int
+	for _, d := range diffs {
+		if d <= 0 {
+			countLE++
+		}
+		if d >= 0 {
+			countGE++
+		}
+	}
+	proportion := math.Min(float64(countLE), float64(countGE)) / float64(iterations)
+	pValue = math.Min(1, 2*proportion)
+
+	return ci, pValue
+}
+
+// analyzePercentiles runs the percentile/tail-latency breakdown requested via
+// AnalysisOptions.Percentiles, comparing benchmark against experiment.
+func analyzePercentiles(benchmark, experiment []float64, opts AnalysisOptions) []PercentileResult {
+	if len(opts.Percentiles) == 0 {
+		return nil
+	}
+
+	results := make([]PercentileResult, 0, len(opts.Percentiles))
+	for _, p := range opts.Percentiles {
+		benchP := percentile(benchmark, p)
+		expP := percentile(experiment, p)
+		ci, pValue := bootstrapPercentileDiff(benchmark, experiment, p, opts.BootstrapIterations, opts.Confidence)
+
+		var diff float64
+		if benchP != 0 {
+			diff = ((expP - benchP) / benchP) * 100
+		}
+
+		results = append(results, PercentileResult{
+			Percentile:         p,
+			Benchmark:          benchP,
+			Experiment:         expP,
+			Difference:         diff,
+			ConfidenceInterval: ci,
+			PValue:             pValue,
+			Significant:        pValue < (1 - opts.Confidence),
+		})
+	}
+	return results
+}
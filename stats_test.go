@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestPercentile(t *testing.T) {
+	values := []float64{10, 20, 30, 40, 50}
+
+	cases := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 10},
+		{50, 30},
+		{100, 50},
+		{25, 20},
+	}
+
+	for _, c := range cases {
+		if got := percentile(values, c.p); got != c.want {
+			t.Errorf("percentile(values, %v) = %v, want %v", c.p, got, c.want)
+		}
+	}
+}
+
+func TestBootstrapPercentileDiff(t *testing.T) {
+	benchmark := []float64{10, 11, 9, 10, 11, 9, 10, 10, 11, 9}
+	experiment := []float64{15, 16, 14, 15, 16, 14, 15, 15, 16, 14}
+
+	ci, pValue := bootstrapPercentileDiff(benchmark, experiment, 50, 500, 0.95)
+
+	if ci[0] > ci[1] {
+		t.Errorf("expected ci[0] <= ci[1], got %v", ci)
+	}
+	if ci[0] <= 0 {
+		t.Errorf("expected a consistently positive difference (experiment always higher) to give a CI entirely above 0, got %v", ci)
+	}
+	if pValue < 0 || pValue > 1 {
+		t.Errorf("p-value must be in [0, 1], got %v", pValue)
+	}
+}
+
+// TestAnalyzeSegmentEffectSizeSignMatchesDifference guards against CohensD/
+// CliffsDelta being computed with the opposite argument order from
+// Difference: a regression (higher experiment values) should read as a
+// positive Cohen's d and Cliff's delta, matching the positive Difference.
+func TestAnalyzeSegmentEffectSizeSignMatchesDifference(t *testing.T) {
+	bench := toMeasurements([]float64{9, 10, 10, 10, 11})
+	exp := toMeasurements([]float64{11, 12, 12, 12, 13})
+
+	result := analyzeSegment(bench, exp, AnalysisOptions{Confidence: 0.95, Test: "welch"})
+
+	if result.Difference <= 0 {
+		t.Fatalf("expected positive Difference for a regression, got %v", result.Difference)
+	}
+	if result.CohensD <= 0 {
+		t.Errorf("expected positive CohensD alongside positive Difference, got %v", result.CohensD)
+	}
+	if result.CliffsDelta <= 0 {
+		t.Errorf("expected positive CliffsDelta alongside positive Difference, got %v", result.CliffsDelta)
+	}
+}